@@ -0,0 +1,384 @@
+// Copyright 2020 The covid19 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package datasource fetches and caches the JHU CSSE CoVid-19 time series,
+// so repeated requests for the same metric don't hit the upstream CSV on
+// every call.
+package datasource
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Dataset is a metric's cumulative time series, aggregated by country (or
+// by province/state, when requested) and aligned on a cutoff.
+type Dataset struct {
+	Date   time.Time
+	Start  time.Time
+	Table  map[string][]float64
+	Cutoff map[string]int
+}
+
+// rawMetrics are the upstream CSV files refreshed together so that
+// "active" (confirmed-deaths-recovered) can always be derived from an
+// in-sync snapshot.
+var rawMetrics = []string{"confirmed", "deaths", "recovered"}
+
+// rawEntry is a cached, parsed upstream CSV, plus the validators needed for
+// a conditional GET on the next refresh.
+type rawEntry struct {
+	fetchedAt    time.Time
+	etag         string
+	lastModified string
+	rows         [][]string
+}
+
+// Cache fetches and caches the JHU CSSE time series. The upstream CSVs are
+// re-downloaded at most once per TTL, using conditional GETs so an
+// unchanged file costs only a round trip. The zero value is not usable;
+// construct with NewCache.
+type Cache struct {
+	Client      *http.Client
+	URLTemplate string // must contain one %s verb for the metric name
+	TTL         time.Duration
+
+	mu  sync.Mutex
+	raw map[string]*rawEntry
+}
+
+// NewCache returns a Cache fetching urlTemplate (with a %s verb for the
+// metric name), re-downloading each metric at most once per ttl.
+func NewCache(urlTemplate string, ttl time.Duration) *Cache {
+	return &Cache{
+		Client:      http.DefaultClient,
+		URLTemplate: urlTemplate,
+		TTL:         ttl,
+		raw:         make(map[string]*rawEntry),
+	}
+}
+
+// Dataset returns the dataset for metric ("confirmed", "deaths",
+// "recovered", or "active"), aggregated over countries and aligned so that
+// day 0 is the first day the cutoff was reached. If province is non-empty,
+// rows are matched against column 0 (Province/State) instead of being
+// aggregated by country.
+func (c *Cache) Dataset(metric string, cutoff float64, countries []string, province string) (Dataset, error) {
+	if metric == "active" {
+		return c.activeDataset(cutoff, countries, province)
+	}
+
+	rows, err := c.rows(metric)
+	if err != nil {
+		return Dataset{}, err
+	}
+	return buildDataset(metric, cutoff, countries, province, rows)
+}
+
+// activeDataset computes confirmed-deaths-recovered from the three
+// underlying metrics, fetched concurrently on a cache miss.
+func (c *Cache) activeDataset(cutoff float64, countries []string, province string) (Dataset, error) {
+	confirmed, err := c.Dataset("confirmed", 0, countries, province)
+	if err != nil {
+		return Dataset{}, fmt.Errorf("datasource: could not fetch confirmed data: %w", err)
+	}
+	deaths, err := c.Dataset("deaths", 0, countries, province)
+	if err != nil {
+		return Dataset{}, fmt.Errorf("datasource: could not fetch deaths data: %w", err)
+	}
+	recovered, err := c.Dataset("recovered", 0, countries, province)
+	if err != nil {
+		return Dataset{}, fmt.Errorf("datasource: could not fetch recovered data: %w", err)
+	}
+
+	ds := Dataset{
+		Date:   confirmed.Date,
+		Start:  confirmed.Start,
+		Table:  make(map[string][]float64, len(countries)),
+		Cutoff: make(map[string]int, len(countries)),
+	}
+	for _, name := range countries {
+		cc, dd, rr := confirmed.Table[name], deaths.Table[name], recovered.Table[name]
+		n := min(len(cc), len(dd), len(rr))
+		active := make([]float64, n)
+		for i := range active {
+			active[i] = cc[i] - dd[i] - rr[i]
+		}
+		ds.Table[name] = active
+	}
+	applyCutoff(&ds, countries, cutoff)
+
+	return ds, nil
+}
+
+// rows returns the parsed CSV rows for metric, refreshing the whole cache
+// (all of rawMetrics, concurrently) if the cached copy is stale or
+// missing. A refresh error is only returned if metric itself is still
+// unpopulated afterwards; a sibling metric failing to refresh (e.g.
+// "recovered", the one most likely to lag upstream) must not fail a
+// request for a metric that refreshed fine.
+func (c *Cache) rows(metric string) ([][]string, error) {
+	c.mu.Lock()
+	entry := c.raw[metric]
+	stale := entry == nil || time.Since(entry.fetchedAt) > c.TTL
+	c.mu.Unlock()
+
+	if stale {
+		refreshErr := c.refresh()
+
+		c.mu.Lock()
+		entry = c.raw[metric]
+		c.mu.Unlock()
+		if entry == nil {
+			if refreshErr != nil {
+				return nil, refreshErr
+			}
+			return nil, fmt.Errorf("datasource: no cached data for %q", metric)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.raw[metric].rows, nil
+}
+
+// refresh re-downloads every metric in rawMetrics concurrently, using a
+// conditional GET for any metric already in cache. It returns the first
+// error encountered, but a per-metric failure still leaves the other
+// metrics' refreshed rows cached; callers should consult c.raw directly
+// rather than treating this error as fatal for every metric.
+func (c *Cache) refresh() error {
+	var g errgroup.Group
+	for _, metric := range rawMetrics {
+		metric := metric
+		g.Go(func() error {
+			return c.refreshOne(metric)
+		})
+	}
+	return g.Wait()
+}
+
+func (c *Cache) refreshOne(metric string) error {
+	c.mu.Lock()
+	prev := c.raw[metric]
+	c.mu.Unlock()
+
+	url := fmt.Sprintf(c.URLTemplate, metric)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("datasource: could not build request for %q: %w", metric, err)
+	}
+	if prev != nil {
+		if prev.etag != "" {
+			req.Header.Set("If-None-Match", prev.etag)
+		}
+		if prev.lastModified != "" {
+			req.Header.Set("If-Modified-Since", prev.lastModified)
+		}
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("datasource: could not retrieve %q data file: %w", metric, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && prev != nil {
+		c.mu.Lock()
+		prev.fetchedAt = time.Now()
+		c.mu.Unlock()
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("datasource: unexpected status fetching %q: %s", metric, resp.Status)
+	}
+
+	raw := csv.NewReader(resp.Body)
+	raw.Comma = ','
+	rows, err := raw.ReadAll()
+	if err != nil {
+		return fmt.Errorf("datasource: could not read %q CSV: %w", metric, err)
+	}
+
+	c.mu.Lock()
+	c.raw[metric] = &rawEntry{
+		fetchedAt:    time.Now(),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		rows:         rows,
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// buildDataset aggregates the already-downloaded CSV rows for metric into
+// a Dataset, applying the cutoff and the known historical data fixes.
+func buildDataset(metric string, cutoff float64, countries []string, province string, rows [][]string) (Dataset, error) {
+	if len(rows) == 0 {
+		return Dataset{}, fmt.Errorf("datasource: empty %q CSV", metric)
+	}
+	hdr := rows[0]
+
+	ds := Dataset{
+		Table:  make(map[string][]float64, len(countries)),
+		Cutoff: make(map[string]int, len(countries)),
+	}
+
+	sz := len(hdr) - 4
+	for _, name := range countries {
+		ds.Table[name] = make([]float64, sz)
+	}
+
+	for _, rec := range rows[1:] {
+		name, ok := matchRow(rec, countries, province)
+		if !ok {
+			continue
+		}
+
+		rec := rec[4:]
+		data := make([]float64, len(rec))
+		for i, str := range rec {
+			if str == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(str, 64)
+			if err != nil {
+				return ds, fmt.Errorf("datasource: could not parse %q: %w", str, err)
+			}
+			data[i] = v
+		}
+		for i, v := range data {
+			ds.Table[name][i] += v
+		}
+	}
+
+	applyCutoff(&ds, countries, cutoff)
+
+	if err := setDates(&ds, hdr); err != nil {
+		return ds, err
+	}
+
+	applyHistoricalFixes(metric, &ds)
+
+	return ds, nil
+}
+
+// matchRow reports whether rec should be aggregated, and under which key.
+// When province is set, rec is matched against column 0 (Province/State);
+// otherwise rec is matched and aggregated by country (column 1).
+func matchRow(rec []string, countries []string, province string) (string, bool) {
+	if province != "" && rec[0] != province {
+		return "", false
+	}
+	for _, name := range countries {
+		if rec[1] == name {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func applyCutoff(ds *Dataset, countries []string, cutoff float64) {
+	for _, name := range countries {
+		data := ds.Table[name]
+		idx := 0
+		for i, v := range data {
+			if v >= cutoff {
+				idx = i
+				break
+			}
+		}
+		ds.Cutoff[name] = idx
+		ds.Table[name] = data[idx:]
+	}
+}
+
+func setDates(ds *Dataset, hdr []string) error {
+	const layout = "1/2/06"
+	for _, v := range []struct {
+		input  string
+		output *time.Time
+	}{
+		{hdr[4], &ds.Start},
+		{hdr[len(hdr)-1], &ds.Date},
+	} {
+		date, err := parseDate(v.input, layout, "1/2/2006")
+		if err != nil {
+			return fmt.Errorf("datasource: could not parse date: %w", err)
+		}
+		*v.output = date
+	}
+	return nil
+}
+
+func parseDate(v string, layouts ...string) (time.Time, error) {
+	var err error
+	for _, layout := range layouts {
+		date, ee := time.Parse(layout, v)
+		if ee == nil {
+			return date, nil
+		}
+		if err == nil {
+			err = ee
+		}
+	}
+	return time.Time{}, err
+}
+
+// applyHistoricalFixes patches a handful of known-bad France data points,
+// carried over from the original hand-maintained dataset.
+func applyHistoricalFixes(metric string, ds *Dataset) {
+	tbl, ok := ds.Table["France"]
+	if !ok {
+		return
+	}
+	switch strings.ToLower(metric) {
+	case "deaths":
+		set(tbl, 2, 30)   // 2020-03-09
+		set(tbl, 10, 175) // 2020-03-17
+		set(tbl, 11, 244) // 2020-03-18
+		set(tbl, 12, 372) // 2020-03-19
+		// tbl[26] = 4503 // 2020-04-02. number was actually correct (includes death toll from EHPADs)
+	case "confirmed":
+		set(tbl, 35, 68605)  // 2020-04-04
+		set(tbl, 36, 70478)  // 2020-04-05
+		set(tbl, 37, 74390)  // 2020-04-06
+		set(tbl, 38, 78167)  // 2020-04-07
+		set(tbl, 39, 82048)  // 2020-04-08
+		set(tbl, 40, 86344)  // 2020-04-09
+		set(tbl, 41, 90676)  // 2020-04-10
+		set(tbl, 42, 93790)  // 2020-04-11
+		set(tbl, 43, 95403)  // 2020-04-12
+		set(tbl, 44, 98076)  // 2020-04-13
+		set(tbl, 45, 103573) // 2020-04-14
+		set(tbl, 46, 106206) // 2020-04-15
+		set(tbl, 47, 108847) // 2020-04-16
+		set(tbl, 48, 109252) // 2020-04-17
+		set(tbl, 49, 111821) // 2020-04-18
+		set(tbl, 50, 112606) // 2020-04-19
+		set(tbl, 51, 114657) // 2020-04-20
+		set(tbl, 52, 117324) // 2020-04-21
+	}
+}
+
+// set assigns v to tbl[i] when i is within bounds; the cutoff-trimmed table
+// may no longer contain these historical indices, in which case it is a
+// no-op.
+func set(tbl []float64, i int, v float64) {
+	if i < 0 || i >= len(tbl) {
+		return
+	}
+	tbl[i] = v
+}
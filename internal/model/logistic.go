@@ -0,0 +1,87 @@
+// Copyright 2020 The covid19 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/optimize"
+)
+
+// Logistic is a logistic-growth fit of the form:
+//
+//	N(t) = K / (1 + exp(-R*(t-T0)))
+//
+// where K is the final epidemic size, R is the growth rate and T0 is the
+// inflection date (in days from the start of the observed series).
+type Logistic struct {
+	K     float64 // final size
+	R     float64 // growth rate
+	T0    float64 // inflection point, in days from t=0
+	Sigma float64 // root-mean-square of the fit residuals; not a standard error on K
+}
+
+// FitLogistic fits a logistic curve to the last n points of the (xs, ys)
+// cumulative series, where xs are expressed in days from the start of the
+// series. If n<=0 or n>len(xs), the whole series is used.
+func FitLogistic(xs, ys []float64, n int) (Logistic, error) {
+	if len(xs) != len(ys) {
+		return Logistic{}, fmt.Errorf("model: xs and ys have different lengths (%d vs %d)", len(xs), len(ys))
+	}
+	if len(xs) == 0 {
+		return Logistic{}, fmt.Errorf("model: no data to fit")
+	}
+	if n <= 0 || n > len(xs) {
+		n = len(xs)
+	}
+	xs = xs[len(xs)-n:]
+	ys = ys[len(ys)-n:]
+
+	last := ys[len(ys)-1]
+	p0 := []float64{last * 2, 0.2, xs[len(xs)/2]}
+
+	problem := optimize.Problem{
+		Func: func(p []float64) float64 {
+			k, r, t0 := p[0], p[1], p[2]
+			var sum float64
+			for i, x := range xs {
+				d := logistic(k, r, t0, x) - ys[i]
+				sum += d * d
+			}
+			return sum
+		},
+	}
+
+	res, err := optimize.Minimize(problem, p0, nil, &optimize.NelderMead{})
+	if err != nil {
+		return Logistic{}, fmt.Errorf("model: could not fit logistic curve: %w", err)
+	}
+
+	k, r, t0 := res.X[0], res.X[1], res.X[2]
+
+	var ss float64
+	for i, x := range xs {
+		d := logistic(k, r, t0, x) - ys[i]
+		ss += d * d
+	}
+	sigma := math.Sqrt(ss / float64(len(xs)))
+
+	return Logistic{K: k, R: r, T0: t0, Sigma: sigma}, nil
+}
+
+func logistic(k, r, t0, t float64) float64 {
+	return k / (1 + math.Exp(-r*(t-t0)))
+}
+
+// Predict implements Fit.
+func (f Logistic) Predict(t float64) float64 {
+	return logistic(f.K, f.R, f.T0, t)
+}
+
+// DoublingTime implements Fit.
+func (f Logistic) DoublingTime() float64 {
+	return doublingTime(f.R)
+}
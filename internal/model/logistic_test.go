@@ -0,0 +1,52 @@
+// Copyright 2020 The covid19 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFitLogisticRecoversKnownCurve(t *testing.T) {
+	const (
+		k  = 10000.0
+		r  = 0.3
+		t0 = 15.0
+	)
+	xs := make([]float64, 30)
+	ys := make([]float64, 30)
+	for i := range xs {
+		xs[i] = float64(i)
+		ys[i] = logistic(k, r, t0, xs[i])
+	}
+
+	fit, err := FitLogistic(xs, ys, 0)
+	if err != nil {
+		t.Fatalf("FitLogistic: %v", err)
+	}
+	if got, want := fit.K, k; math.Abs(got-want)/want > 0.01 {
+		t.Errorf("fit.K = %v, want ~%v", got, want)
+	}
+	if got, want := fit.R, r; math.Abs(got-want)/want > 0.01 {
+		t.Errorf("fit.R = %v, want ~%v", got, want)
+	}
+	if got, want := fit.T0, t0; math.Abs(got-want)/want > 0.01 {
+		t.Errorf("fit.T0 = %v, want ~%v", got, want)
+	}
+}
+
+func TestFitLogisticRejectsMismatchedLengths(t *testing.T) {
+	_, err := FitLogistic([]float64{1, 2}, []float64{1}, 0)
+	if err == nil {
+		t.Fatal("FitLogistic with mismatched xs/ys: got nil error, want one")
+	}
+}
+
+func TestFitLogisticRejectsEmptySeries(t *testing.T) {
+	_, err := FitLogistic(nil, nil, 0)
+	if err == nil {
+		t.Fatal("FitLogistic with no data: got nil error, want one")
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 The covid19 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package model implements simple epidemiological models (logistic growth,
+// SIR) fitted against observed cumulative case counts, so plots can overlay
+// a short-term forecast on top of the empirical series.
+package model
+
+import (
+	"math"
+)
+
+// Fit is a fitted model, able to predict the cumulative count at time t
+// (in days from the start of the observed series).
+type Fit interface {
+	// Predict returns the predicted cumulative value at time t.
+	Predict(t float64) float64
+
+	// DoublingTime returns the current doubling time, ln(2)/r, where r is
+	// the model's instantaneous growth rate.
+	DoublingTime() float64
+}
+
+// maxDoublingTime caps the reported doubling time, in days, for a
+// non-growing fit. The quantity isn't currently doubling at all in that
+// case, but +Inf doesn't format usefully into a legend label, so a
+// large-but-finite value is reported instead.
+const maxDoublingTime = 1000
+
+// doublingTime returns ln(2)/r, the time needed for a quantity growing at
+// rate r to double, capped at maxDoublingTime. For r<=0 (no growth or
+// decay), maxDoublingTime is reported.
+func doublingTime(r float64) float64 {
+	if r <= 0 {
+		return maxDoublingTime
+	}
+	d := math.Ln2 / r
+	if d > maxDoublingTime {
+		return maxDoublingTime
+	}
+	return d
+}
@@ -0,0 +1,33 @@
+// Copyright 2020 The covid19 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDoublingTime(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		rate float64
+		want float64
+	}{
+		{name: "positive rate", rate: math.Ln2, want: 1},
+		{name: "no growth", rate: 0, want: maxDoublingTime},
+		{name: "decay", rate: -0.1, want: maxDoublingTime},
+		{name: "very slow growth capped", rate: 1e-6, want: maxDoublingTime},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := doublingTime(tc.rate)
+			if math.IsInf(got, 0) || math.IsNaN(got) {
+				t.Fatalf("doublingTime(%v) = %v, want a finite, plottable value", tc.rate, got)
+			}
+			if math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("doublingTime(%v) = %v, want %v", tc.rate, got, tc.want)
+			}
+		})
+	}
+}
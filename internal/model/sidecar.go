@@ -0,0 +1,80 @@
+// Copyright 2020 The covid19 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sidecar is the on-disk representation of a fit, persisted alongside a
+// run so that successive runs can show how the fitted parameters drift.
+type Sidecar struct {
+	Date     time.Time `json:"date"`
+	Metric   string    `json:"metric"`
+	Country  string    `json:"country"`
+	Logistic *Logistic `json:"logistic,omitempty"`
+	SIR      *SIR      `json:"sir,omitempty"`
+}
+
+// LoadSidecar reads the list of Sidecar entries persisted at path. It is
+// not an error if path does not exist; a zero-value slice is returned
+// instead.
+func LoadSidecar(path string) ([]Sidecar, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("model: could not read sidecar %q: %w", path, err)
+	}
+
+	var sidecars []Sidecar
+	if err := json.Unmarshal(raw, &sidecars); err != nil {
+		return nil, fmt.Errorf("model: could not decode sidecar %q: %w", path, err)
+	}
+	return sidecars, nil
+}
+
+// maxSidecarEntries bounds how many fits AppendSidecar retains per path,
+// so a long-running server's sidecar file (and the cost of rewriting it
+// on every append) doesn't grow without bound. Once the cap is reached,
+// the oldest entries are dropped.
+const maxSidecarEntries = 500
+
+// appendMu serializes AppendSidecar's read-modify-write across concurrent
+// callers (e.g. two forecast renders served at once), so one writer's fit
+// history isn't silently dropped by the other.
+var appendMu sync.Mutex
+
+// AppendSidecar appends entry to the list of fits persisted at path,
+// creating the file if needed, dropping the oldest entries past
+// maxSidecarEntries.
+func AppendSidecar(path string, entry Sidecar) error {
+	appendMu.Lock()
+	defer appendMu.Unlock()
+
+	sidecars, err := LoadSidecar(path)
+	if err != nil {
+		return err
+	}
+	sidecars = append(sidecars, entry)
+	if len(sidecars) > maxSidecarEntries {
+		sidecars = sidecars[len(sidecars)-maxSidecarEntries:]
+	}
+
+	raw, err := json.MarshalIndent(sidecars, "", "  ")
+	if err != nil {
+		return fmt.Errorf("model: could not encode sidecar %q: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		return fmt.Errorf("model: could not write sidecar %q: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 The covid19 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/optimize"
+)
+
+// SIR is a discrete Susceptible-Infected-Recovered model, integrated by
+// RK4, fitted against an observed cumulative-infected series.
+type SIR struct {
+	Beta       float64 // infection rate
+	Gamma      float64 // recovery rate
+	Population float64
+}
+
+// R0 returns the basic reproduction number, beta/gamma.
+func (f SIR) R0() float64 {
+	return f.Beta / f.Gamma
+}
+
+// DoublingTime implements Fit, using beta-gamma as the early-phase growth
+// rate of the infected compartment.
+func (f SIR) DoublingTime() float64 {
+	return doublingTime(f.Beta - f.Gamma)
+}
+
+// Predict implements Fit, returning the cumulative number of infections
+// (I+R) at day t, integrating from t=0 with one infected seed. The model
+// is integrated in whole-day steps, so a fractional t is rounded up to the
+// next whole day.
+func (f SIR) Predict(t float64) float64 {
+	if t < 0 {
+		return 0
+	}
+	steps := int(math.Ceil(t))
+	_, i, r := sir(f.Beta, f.Gamma, f.Population, steps)
+	return i[len(i)-1] + r[len(r)-1]
+}
+
+// sir integrates the SIR ODEs over [0,steps] days using RK4, seeding the
+// infected compartment with a single case.
+func sir(beta, gamma, population float64, steps int) (s, i, r []float64) {
+	s = make([]float64, steps+1)
+	i = make([]float64, steps+1)
+	r = make([]float64, steps+1)
+
+	s[0] = population - 1
+	i[0] = 1
+	r[0] = 0
+
+	deriv := func(s, i float64) (ds, di, dr float64) {
+		ds = -beta * s * i / population
+		di = beta*s*i/population - gamma*i
+		dr = gamma * i
+		return ds, di, dr
+	}
+
+	const dt = 1.0
+	for n := 0; n < steps; n++ {
+		k1s, k1i, k1r := deriv(s[n], i[n])
+		k2s, k2i, k2r := deriv(s[n]+dt/2*k1s, i[n]+dt/2*k1i)
+		k3s, k3i, k3r := deriv(s[n]+dt/2*k2s, i[n]+dt/2*k2i)
+		k4s, k4i, k4r := deriv(s[n]+dt*k3s, i[n]+dt*k3i)
+
+		s[n+1] = s[n] + dt/6*(k1s+2*k2s+2*k3s+k4s)
+		i[n+1] = i[n] + dt/6*(k1i+2*k2i+2*k3i+k4i)
+		r[n+1] = r[n] + dt/6*(k1r+2*k2r+2*k3r+k4r)
+	}
+
+	return s, i, r
+}
+
+// FitSIR fits beta and gamma against the observed cumulative-infected
+// series (ys, one point per day starting at t=0) for the given population,
+// minimizing squared log-residuals.
+func FitSIR(population float64, ys []float64) (SIR, error) {
+	if len(ys) == 0 {
+		return SIR{}, fmt.Errorf("model: no data to fit")
+	}
+	if population <= 0 {
+		return SIR{}, fmt.Errorf("model: invalid population %v", population)
+	}
+
+	p0 := []float64{0.3, 0.1}
+
+	problem := optimize.Problem{
+		Func: func(p []float64) float64 {
+			beta, gamma := p[0], p[1]
+			if beta <= 0 || gamma <= 0 {
+				return math.Inf(1)
+			}
+			_, i, r := sir(beta, gamma, population, len(ys)-1)
+			var sum float64
+			for n, y := range ys {
+				pred := i[n] + r[n]
+				if pred <= 0 || y <= 0 {
+					continue
+				}
+				d := math.Log(pred) - math.Log(y)
+				sum += d * d
+			}
+			return sum
+		},
+	}
+
+	res, err := optimize.Minimize(problem, p0, nil, &optimize.NelderMead{})
+	if err != nil {
+		return SIR{}, fmt.Errorf("model: could not fit SIR model: %w", err)
+	}
+
+	return SIR{Beta: res.X[0], Gamma: res.X[1], Population: population}, nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 The covid19 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSIRConservesPopulation(t *testing.T) {
+	const population = 1e6
+	s, i, r := sir(0.4, 0.1, population, 30)
+	for n := range s {
+		total := s[n] + i[n] + r[n]
+		if math.Abs(total-population) > 1e-6 {
+			t.Fatalf("s+i+r at step %d = %v, want population %v", n, total, population)
+		}
+	}
+}
+
+func TestSIRPredictAtZeroIsSeed(t *testing.T) {
+	f := SIR{Beta: 0.4, Gamma: 0.1, Population: 1e6}
+	got := f.Predict(0)
+	if math.Abs(got-1) > 1e-9 {
+		t.Errorf("Predict(0) = %v, want 1 (the seeded infection)", got)
+	}
+}
+
+func TestSIRPredictNegativeIsZero(t *testing.T) {
+	f := SIR{Beta: 0.4, Gamma: 0.1, Population: 1e6}
+	if got := f.Predict(-1); got != 0 {
+		t.Errorf("Predict(-1) = %v, want 0", got)
+	}
+}
+
+func TestSIRR0(t *testing.T) {
+	f := SIR{Beta: 0.4, Gamma: 0.1, Population: 1e6}
+	if got, want := f.R0(), 4.0; got != want {
+		t.Errorf("R0() = %v, want %v", got, want)
+	}
+}
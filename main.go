@@ -5,103 +5,330 @@
 package main
 
 import (
-	"encoding/csv"
+	"bytes"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
 	"image/png"
-	"io"
 	"log"
 	"math"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"go-hep.org/x/hep/hplot"
-	"gonum.org/v1/gonum/floats"
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotutil"
 	"gonum.org/v1/plot/vg"
 	"gonum.org/v1/plot/vg/draw"
 	"gonum.org/v1/plot/vg/vgimg"
+	"gonum.org/v1/plot/vg/vgsvg"
+
+	"github.com/sbinet/covid19/internal/datasource"
+	"github.com/sbinet/covid19/internal/model"
 )
 
+var (
+	defaultCountries = []string{
+		"France",
+		"Italy",
+		"Spain",
+		"Germany",
+		"US",
+		"United Kingdom",
+	}
+
+	addr            = flag.String("addr", ":8080", "server address to listen on")
+	countries       = flag.String("countries", strings.Join(defaultCountries, ","), "comma-separated list of default countries to plot")
+	cutoff          = flag.Float64("cutoff", 100, "default cutoff used to align time series")
+	dataURL         = flag.String("data-url", "https://raw.githubusercontent.com/CSSEGISandData/COVID-19/master/csse_covid_19_data/csse_covid_19_time_series/time_series_covid19_%s_global.csv", "URL template (with a %s verb for the metric) for the JHU CSSE dataset")
+	sidecar         = flag.String("model-sidecar", "covid-fits.json", "path to the JSON sidecar recording fitted model parameters across runs")
+	cacheTTL        = flag.Duration("cache-ttl", time.Hour, "how long to cache the upstream CSV and rendered plots before re-fetching")
+	cacheMaxEntries = flag.Int("cache-max-entries", 256, "maximum number of distinct plot configurations to keep rendered plots for")
+)
+
+// forecastDays is how far past the last observed day a fitted model curve
+// is extrapolated.
+const forecastDays = 14
+
+// dataCache fetches and caches the upstream JHU CSSE CSVs.
+var dataCache *datasource.Cache
+
+// plotCache caches rendered PNG/SVG bytes per plot configuration, so that
+// repeated requests within cacheTTL don't re-fit/re-draw the plot.
+var plotCache *renderCache
+
 func main() {
+	flag.Parse()
+
 	log.SetPrefix("covid19: ")
 	log.SetFlags(0)
 
+	dataCache = datasource.NewCache(*dataURL, *cacheTTL)
+	plotCache = newRenderCache(*cacheTTL, *cacheMaxEntries)
+
 	http.HandleFunc("/", rootHandle)
-	http.HandleFunc("/img-confirmed", imgHandle("confirmed", 100))
+	http.HandleFunc("/img-confirmed", imgHandle("confirmed", *cutoff))
 	http.HandleFunc("/img-deaths", imgHandle("deaths", 10))
+	http.HandleFunc("/img", imgHandle("confirmed", *cutoff))
+	http.HandleFunc("/svg-confirmed", svgHandle("confirmed", *cutoff))
+	http.HandleFunc("/svg-deaths", svgHandle("deaths", 10))
+	http.HandleFunc("/data.json", dataHandle("confirmed", *cutoff))
 	log.Printf("ready to serve...")
-	http.ListenAndServe(":8080", nil)
+	log.Fatal(http.ListenAndServe(*addr, nil))
 }
 
 func rootHandle(w http.ResponseWriter, req *http.Request) {
 	fmt.Fprintf(w, page)
 }
 
+// imgHandle returns an HTTP handler rendering title/cutoff by default,
+// which may be overridden by the "metric", "cutoff", "countries",
+// "province" and "scale" query parameters. The response format is SVG,
+// unless the request's Accept header asks for "image/png" without also
+// listing "image/svg+xml", in which case PNG is served as a fallback.
 func imgHandle(title string, cutoff float64) func(w http.ResponseWriter, req *http.Request) {
 	return func(w http.ResponseWriter, req *http.Request) {
-		img, err := genImage(title, cutoff)
+		cfg, err := newPlotConfig(req, title, cutoff)
 		if err != nil {
 			log.Printf("error: %+v", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		err = png.Encode(w, img)
+		if negotiateFormat(req) == "png" {
+			img, err := plotCache.png(cfg)
+			if err != nil {
+				log.Printf("error: %+v", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(img)
+			return
+		}
+
+		svg, err := plotCache.svg(cfg)
 		if err != nil {
 			log.Printf("error: %+v", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(svg)
+	}
+}
+
+// svgHandle returns an HTTP handler unconditionally rendering title/cutoff
+// (overridable the same way as imgHandle) as SVG.
+func svgHandle(title string, cutoff float64) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		cfg, err := newPlotConfig(req, title, cutoff)
+		if err != nil {
+			log.Printf("error: %+v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		f, err := os.Create("covid-" + strings.ToLower(title) + ".png")
+		svg, err := plotCache.svg(cfg)
 		if err != nil {
 			log.Printf("error: %+v", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(svg)
+	}
+}
+
+// negotiateFormat picks a rendering format from the request's Accept
+// header: SVG by default, falling back to PNG when the client explicitly
+// asks for it.
+func negotiateFormat(req *http.Request) string {
+	accept := req.Header.Get("Accept")
+	if strings.Contains(accept, "image/svg+xml") {
+		return "svg"
+	}
+	if strings.Contains(accept, "image/png") {
+		return "png"
+	}
+	return "svg"
+}
+
+// dataHandle returns an HTTP handler serving the dataset backing
+// title/cutoff (overridable the same way as imgHandle) as JSON, so
+// front-ends can render their own interactive charts.
+func dataHandle(title string, cutoff float64) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		cfg, err := newPlotConfig(req, title, cutoff)
+		if err != nil {
+			log.Printf("error: %+v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-		defer f.Close()
-		err = png.Encode(f, img)
+
+		ds, err := dataCache.Dataset(cfg.metric, cfg.cutoff, cfg.countries, cfg.province)
 		if err != nil {
 			log.Printf("error: %+v", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(datasetPayload(cfg.metric, ds)); err != nil {
+			log.Printf("error: %+v", err)
 		}
 	}
 }
 
-func genImage(title string, cutoff float64) (image.Image, error) {
-	countries := []string{
-		"France",
-		"Italy",
-		"Spain",
-		//	"Korea, South",
-		//	"China",
-		"Germany",
-		"US",
-		"United Kingdom",
+// seriesJSON is the JSON representation of a single country's series.
+type seriesJSON struct {
+	Dates      []string  `json:"dates"`
+	Cumulative []float64 `json:"cumulative"`
+	Daily      []float64 `json:"daily"`
+	Lockdown   string    `json:"lockdown,omitempty"`
+}
+
+// datasetPayload builds the JSON-serializable view of a Dataset returned
+// by /data.json.
+func datasetPayload(metric string, ds datasource.Dataset) map[string]interface{} {
+	countries := make(map[string]seriesJSON, len(ds.Table))
+	for name, cumulative := range ds.Table {
+		start := ds.Start.AddDate(0, 0, ds.Cutoff[name])
+		dates := make([]string, len(cumulative))
+		daily := make([]float64, len(cumulative))
+		for i, v := range cumulative {
+			dates[i] = start.AddDate(0, 0, i).Format("2006-01-02")
+			if i > 0 {
+				daily[i] = math.Max(0, v-cumulative[i-1])
+			}
+		}
+		s := seriesJSON{Dates: dates, Cumulative: cumulative, Daily: daily}
+		if lockdown, ok := lockDB[name]; ok {
+			s.Lockdown = lockdown.Format("2006-01-02")
+		}
+		countries[name] = s
+	}
+
+	return map[string]interface{}{
+		"metric":    metric,
+		"as_of":     ds.Date.Format("2006-01-02"),
+		"countries": countries,
+	}
+}
+
+// validViews are the additional derived-series tiles selectable via the
+// "views" query parameter, appended after the cumulative/daily tiles.
+var validViews = map[string]bool{
+	"ma7":       true, // 7-day trailing moving average of daily counts
+	"percapita": true, // cumulative count per 100k inhabitants
+	"doubling":  true, // instantaneous doubling time
+}
+
+// plotConfig holds the parameters driving a single rendering of buildPlot,
+// merging server-wide defaults (flags) with per-request query parameters.
+type plotConfig struct {
+	metric     string
+	cutoff     float64
+	countries  []string
+	province   string
+	scale      string // "log" or "linear"
+	model      string // "", "logistic" or "sir"
+	population float64
+	views      []string // additional tiles: "ma7", "percapita", "doubling"
+}
+
+func newPlotConfig(req *http.Request, metric string, cutoff float64) (plotConfig, error) {
+	cfg := plotConfig{
+		metric:    metric,
+		cutoff:    cutoff,
+		countries: strings.Split(*countries, ","),
+		scale:     "log",
+	}
+
+	q := req.URL.Query()
+	if v := q.Get("metric"); v != "" {
+		cfg.metric = v
+	}
+	if v := q.Get("cutoff"); v != "" {
+		c, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("could not parse cutoff %q: %w", v, err)
+		}
+		cfg.cutoff = c
 	}
-	ds, err := fetchData(title, cutoff, countries)
+	if v := q.Get("countries"); v != "" {
+		cfg.countries = strings.Split(v, ",")
+	}
+	if v := q.Get("province"); v != "" {
+		cfg.province = v
+	}
+	if v := q.Get("scale"); v != "" {
+		switch v {
+		case "log", "linear":
+			cfg.scale = v
+		default:
+			return cfg, fmt.Errorf("invalid scale %q: want %q or %q", v, "log", "linear")
+		}
+	}
+	if v := q.Get("model"); v != "" {
+		switch v {
+		case "logistic", "sir":
+			cfg.model = v
+		default:
+			return cfg, fmt.Errorf("invalid model %q: want %q or %q", v, "logistic", "sir")
+		}
+	}
+	if v := q.Get("population"); v != "" {
+		p, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("could not parse population %q: %w", v, err)
+		}
+		cfg.population = p
+	}
+	if v := q.Get("views"); v != "" {
+		views := strings.Split(v, ",")
+		for _, view := range views {
+			if !validViews[view] {
+				return cfg, fmt.Errorf("invalid view %q: want one of %q", view, []string{"ma7", "percapita", "doubling"})
+			}
+		}
+		cfg.views = views
+	}
+
+	return cfg, nil
+}
+
+// buildPlot fetches the dataset for cfg and lays out the cumulative/daily
+// tiled plot, plus one tile per entry of cfg.views. The returned Dataset is
+// the one used to build the plot, so that callers needing the raw series
+// don't have to re-fetch it.
+func buildPlot(cfg plotConfig) (*hplot.TiledPlot, datasource.Dataset, error) {
+	countries := cfg.countries
+	ds, err := dataCache.Dataset(cfg.metric, cfg.cutoff, countries, cfg.province)
 	if err != nil {
-		return nil, fmt.Errorf("could not fetch data: %w", err)
+		return nil, ds, fmt.Errorf("could not fetch data: %w", err)
 	}
-	date := ds.date
-	dataset := ds.table
-	log.Printf("%s: data for %q", title, date.Format("2006-01-02"))
+	date := ds.Date
+	dataset := ds.Table
+	log.Printf("%s: data for %q", cfg.metric, date.Format("2006-01-02"))
 
-	tp := hplot.NewTiledPlot(draw.Tiles{Rows: 2, Cols: 1})
+	tp := hplot.NewTiledPlot(draw.Tiles{Rows: 2 + len(cfg.views), Cols: 1})
 	tp.Align = true
 
 	{
 		p := tp.Plots[0]
-		p.Title.Text = "CoVid-19 - " + title + " (cumulative) - " + date.Format("2006-01-02")
-		p.X.Label.Text = fmt.Sprintf("Days from first %d confirmed cases", int(cutoff))
+		p.Title.Text = "CoVid-19 - " + cfg.metric + " (cumulative) - " + date.Format("2006-01-02")
+		p.X.Label.Text = fmt.Sprintf("Days from first %d confirmed cases", int(cfg.cutoff))
 		p.X.Tick.Marker = hplot.Ticks{N: 20}
-		p.Y.Scale = plot.LogScale{}
-		p.Y.Tick.Marker = plot.LogTicks{}
+		if cfg.scale == "log" {
+			p.Y.Scale = plot.LogScale{}
+			p.Y.Tick.Marker = plot.LogTicks{}
+		}
 
 		legends := make(map[string]plot.Thumbnailer)
 		for i, name := range countries {
@@ -113,15 +340,20 @@ func genImage(title string, cutoff float64) (image.Image, error) {
 			xys := hplot.ZipXY(xs, ys)
 			line, err := hplot.NewLine(xys)
 			if err != nil {
-				return nil, fmt.Errorf("could not create line plot for %q: %w", name, err)
+				return nil, ds, fmt.Errorf("could not create line plot for %q: %w", name, err)
 			}
 			line.Color = softcolor(i)
 			line.Width = 2
 			p.Add(line)
 			p.Legend.Add(fmt.Sprintf("%s %8d", name, int(ys[len(ys)-1])), line)
+			if cfg.model != "" {
+				if err := addForecast(p, cfg, name, xs, ys, line.Color); err != nil {
+					return nil, ds, fmt.Errorf("could not add forecast for %q: %w", name, err)
+				}
+			}
 			if lockdown, ok := lockDB[name]; ok {
-				v := ds.cutoff[name]
-				start := ds.start
+				v := ds.Cutoff[name]
+				start := ds.Start
 				loc := start.Location()
 				beg := time.Date(start.Year(), start.Month(), start.Day()+v, 0, 0, 0, 0, loc)
 				lx := lockdown.Sub(beg).Hours() / 24
@@ -134,7 +366,7 @@ func genImage(title string, cutoff float64) (image.Image, error) {
 			}
 		}
 		fct := hplot.NewFunction(func(x float64) float64 {
-			return cutoff * math.Pow(1.33, x)
+			return cfg.cutoff * math.Pow(1.33, x)
 		})
 		fct.LineStyle.Color = color.Gray16{}
 		fct.LineStyle.Width = 2
@@ -142,15 +374,17 @@ func genImage(title string, cutoff float64) (image.Image, error) {
 		p.Add(fct)
 		p.Legend.Add("33% daily growth", fct)
 		for _, name := range []string{"Italy", "France", "United Kingdom"} {
-			p.Legend.Add(fmt.Sprintf("%s - lockdown", name), legends[name])
+			if vline, ok := legends[name]; ok {
+				p.Legend.Add(fmt.Sprintf("%s - lockdown", name), vline)
+			}
 		}
 		p.Add(hplot.NewGrid())
 	}
 
 	{
 		p := tp.Plots[1]
-		p.Title.Text = "CoVid-19 - " + title + " (daily) - " + date.Format("2006-01-02")
-		p.X.Label.Text = fmt.Sprintf("Days from first %d confirmed cases", int(cutoff))
+		p.Title.Text = "CoVid-19 - " + cfg.metric + " (daily) - " + date.Format("2006-01-02")
+		p.X.Label.Text = fmt.Sprintf("Days from first %d confirmed cases", int(cfg.cutoff))
 		p.X.Tick.Marker = hplot.Ticks{N: 20}
 		p.Y.Tick.Marker = hplot.Ticks{N: 20}
 		p.Legend.Left = true
@@ -158,15 +392,7 @@ func genImage(title string, cutoff float64) (image.Image, error) {
 
 		legends := make(map[string]plot.Thumbnailer)
 		for i, name := range countries {
-			ys := make([]float64, len(dataset[name]))
-			copy(ys, dataset[name])
-			for i := range ys {
-				if i == 0 {
-					continue
-				}
-
-				ys[i] = math.Max(0, ys[i]-dataset[name][i-1])
-			}
+			ys := dailyDiffs(dataset[name])
 			xs := make([]float64, len(ys))
 			for i := range xs {
 				xs[i] = float64(i)
@@ -174,15 +400,15 @@ func genImage(title string, cutoff float64) (image.Image, error) {
 			xys := hplot.ZipXY(xs, ys)
 			line, err := hplot.NewLine(xys)
 			if err != nil {
-				return nil, fmt.Errorf("could not create line plot for %q: %w", name, err)
+				return nil, ds, fmt.Errorf("could not create line plot for %q: %w", name, err)
 			}
 			line.Color = softcolor(i)
 			line.Width = 2
 			p.Add(line)
 			p.Legend.Add(fmt.Sprintf("%8d %s", int(ys[len(ys)-1]), name), line)
 			if lockdown, ok := lockDB[name]; ok {
-				v := ds.cutoff[name]
-				start := ds.start
+				v := ds.Cutoff[name]
+				start := ds.Start
 				loc := start.Location()
 				beg := time.Date(start.Year(), start.Month(), start.Day()+v, 0, 0, 0, 0, loc)
 				lx := lockdown.Sub(beg).Hours() / 24
@@ -195,163 +421,246 @@ func genImage(title string, cutoff float64) (image.Image, error) {
 			}
 		}
 		for _, name := range []string{"Italy", "France", "United Kingdom"} {
-			p.Legend.Add(fmt.Sprintf("%s - lockdown", name), legends[name])
+			if vline, ok := legends[name]; ok {
+				p.Legend.Add(fmt.Sprintf("%s - lockdown", name), vline)
+			}
 		}
 		p.Add(hplot.NewGrid())
 	}
 
-	const sz = 20 * vg.Centimeter
-	cnv := vgimg.PngCanvas{vgimg.New(sz*math.Phi, 2*sz)}
+	for i, view := range cfg.views {
+		p := tp.Plots[2+i]
+		if err := addViewTile(p, view, cfg, countries, dataset, date); err != nil {
+			return nil, ds, fmt.Errorf("could not add %q view: %w", view, err)
+		}
+	}
+
+	return tp, ds, nil
+}
+
+// plotSize is the rendered dimensions of a tiled plot, shared by the PNG
+// and SVG backends so both formats line up.
+const plotSize = 20 * vg.Centimeter
 
+// renderPNG rasterizes a tiled plot to a PNG image.
+func renderPNG(tp *hplot.TiledPlot) image.Image {
+	cnv := vgimg.PngCanvas{vgimg.New(plotSize*math.Phi, 2*plotSize)}
 	c := draw.New(cnv)
 	tp.Draw(c)
-	return cnv.Image(), nil
+	return cnv.Image()
+}
+
+// renderSVG renders a tiled plot to SVG, as in the go-hep pinggraph
+// example.
+func renderSVG(tp *hplot.TiledPlot) ([]byte, error) {
+	cnv := vgsvg.New(plotSize*math.Phi, 2*plotSize)
+	c := draw.New(cnv)
+	tp.Draw(c)
+
+	var buf bytes.Buffer
+	if _, err := cnv.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("could not render SVG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderEntry holds the cached PNG/SVG bytes for a single plot
+// configuration, each with its own freshness timestamp since a request may
+// ask for one format without the other ever being rendered.
+type renderEntry struct {
+	png      []byte
+	pngAt    time.Time
+	svg      []byte
+	svgAt    time.Time
+	lastUsed time.Time
+}
+
+// renderCache caches rendered PNG/SVG plots, keyed by plot configuration,
+// re-rendering at most once per TTL. Since the key is built from
+// arbitrary, request-supplied fields (countries, province, ...), the
+// number of distinct configurations is unbounded; maxEntries caps the
+// cache's memory use by evicting the least-recently-used entry once the
+// cache is full.
+type renderCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*renderEntry
 }
 
-type Dataset struct {
-	date   time.Time
-	start  time.Time
-	table  map[string][]float64
-	cutoff map[string]int
+func newRenderCache(ttl time.Duration, maxEntries int) *renderCache {
+	return &renderCache{ttl: ttl, maxEntries: maxEntries, entries: make(map[string]*renderEntry)}
 }
 
-func fetchData(title string, cutoff float64, countries []string) (Dataset, error) {
-	url := fmt.Sprintf("https://raw.githubusercontent.com/CSSEGISandData/COVID-19/master/csse_covid_19_data/csse_covid_19_time_series/time_series_covid19_%s_global.csv", title)
+func (c *renderCache) key(cfg plotConfig) string {
+	return strings.Join([]string{
+		cfg.metric,
+		strconv.FormatFloat(cfg.cutoff, 'g', -1, 64),
+		strings.Join(cfg.countries, ","),
+		cfg.province,
+		cfg.scale,
+		cfg.model,
+		strconv.FormatFloat(cfg.population, 'g', -1, 64),
+		strings.Join(cfg.views, ","),
+	}, "|")
+}
 
-	var dataset = Dataset{
-		table:  make(map[string][]float64, len(countries)),
-		cutoff: make(map[string]int, len(countries)),
+// png returns the cached PNG bytes for cfg, rendering (and caching) them
+// if the cache is stale or empty.
+func (c *renderCache) png(cfg plotConfig) ([]byte, error) {
+	k := c.key(cfg)
+
+	c.mu.Lock()
+	e := c.entries[k]
+	if e != nil && e.png != nil && time.Since(e.pngAt) < c.ttl {
+		e.lastUsed = time.Now()
+		defer c.mu.Unlock()
+		return e.png, nil
 	}
+	c.mu.Unlock()
 
-	resp, err := http.Get(url)
+	tp, _, err := buildPlot(cfg)
 	if err != nil {
-		return dataset, fmt.Errorf("could not retrieve data file: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	raw := csv.NewReader(resp.Body)
-	raw.Comma = ','
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, renderPNG(tp)); err != nil {
+		return nil, fmt.Errorf("could not encode PNG: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e = c.entry(k)
+	e.png, e.pngAt = buf.Bytes(), time.Now()
+	return e.png, nil
+}
+
+// svg returns the cached SVG bytes for cfg, rendering (and caching) them
+// if the cache is stale or empty.
+func (c *renderCache) svg(cfg plotConfig) ([]byte, error) {
+	k := c.key(cfg)
+
+	c.mu.Lock()
+	e := c.entries[k]
+	if e != nil && e.svg != nil && time.Since(e.svgAt) < c.ttl {
+		e.lastUsed = time.Now()
+		defer c.mu.Unlock()
+		return e.svg, nil
+	}
+	c.mu.Unlock()
 
-	hdr, err := raw.Read()
+	tp, _, err := buildPlot(cfg)
 	if err != nil {
-		return dataset, fmt.Errorf("could not read CSV header: %w", err)
+		return nil, err
 	}
 
-	sz := len(hdr) - 4
-	for _, name := range countries {
-		dataset.table[name] = make([]float64, sz)
+	svg, err := renderSVG(tp)
+	if err != nil {
+		return nil, err
 	}
 
-loop:
-	for {
-		rec, err := raw.Read()
-		if err != nil {
-			if err == io.EOF {
-				break loop
-			}
-			return dataset, fmt.Errorf("could not read CSV data: %w", err)
-		}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e = c.entry(k)
+	e.svg, e.svgAt = svg, time.Now()
+	return e.svg, nil
+}
 
-		if _, ok := dataset.table[rec[1]]; !ok {
-			continue
+// entry returns the renderEntry for k, creating it if necessary, evicting
+// the least-recently-used entry first if the cache is at capacity.
+// Callers must hold c.mu.
+func (c *renderCache) entry(k string) *renderEntry {
+	e, ok := c.entries[k]
+	if ok {
+		e.lastUsed = time.Now()
+		return e
+	}
+	if c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.evictLRU()
+	}
+	e = &renderEntry{lastUsed: time.Now()}
+	c.entries[k] = e
+	return e
+}
+
+// evictLRU drops the least-recently-used entry. Callers must hold c.mu.
+func (c *renderCache) evictLRU() {
+	var oldestKey string
+	var oldest time.Time
+	for k, e := range c.entries {
+		if oldestKey == "" || e.lastUsed.Before(oldest) {
+			oldestKey, oldest = k, e.lastUsed
 		}
+	}
+	if oldestKey != "" {
+		delete(c.entries, oldestKey)
+	}
+}
 
-		name := rec[1]
-		rec = rec[4:]
-		data := make([]float64, len(rec))
-		for i, str := range rec {
-			if str == "" {
-				continue
-			}
-			v, err := strconv.ParseFloat(str, 64)
-			if err != nil {
-				return dataset, fmt.Errorf("could not parse %q: %w", str, err)
-			}
-			data[i] = v
+// addForecast fits cfg.model against the observed (xs, ys) cumulative
+// series for name, draws the fitted curve extrapolated forecastDays past
+// the last observation, annotates the inflection date with a VLine, adds a
+// legend entry summarizing the fit, and appends the fit to the model
+// sidecar.
+func addForecast(p *hplot.Plot, cfg plotConfig, name string, xs, ys []float64, clr color.Color) error {
+	var (
+		fit           model.Fit
+		entry         model.Sidecar
+		inflection    float64
+		hasInflection bool
+	)
+
+	switch cfg.model {
+	case "logistic":
+		f, err := model.FitLogistic(xs, ys, 0)
+		if err != nil {
+			return err
 		}
-		floats.Add(dataset.table[name], data)
-	}
-
-	for _, name := range countries {
-		data := dataset.table[name]
-		idx := 0
-	cleanup:
-		for i, v := range data {
-			if v >= cutoff {
-				idx = i
-				dataset.cutoff[name] = idx
-				break cleanup
-			}
+		fit, entry.Logistic = f, &f
+		inflection, hasInflection = f.T0, true
+	case "sir":
+		population := cfg.population
+		if population == 0 {
+			return fmt.Errorf("model %q requires a population (see ?population)", cfg.model)
 		}
-		dataset.table[name] = data[idx:]
-	}
-
-	const layout = "1/2/06"
-	for _, v := range []struct {
-		input  string
-		output *time.Time
-	}{
-		{hdr[4], &dataset.start},
-		{hdr[len(hdr)-1], &dataset.date},
-	} {
-		date, err := parseDate(v.input, layout, "1/2/2006")
+		f, err := model.FitSIR(population, ys)
 		if err != nil {
-			return dataset, fmt.Errorf("could not parse date: %w", err)
+			return err
 		}
-		*v.output = date
+		fit, entry.SIR = f, &f
+	default:
+		return fmt.Errorf("unknown model %q", cfg.model)
 	}
 
-	cleanup(title, &dataset)
-
-	return dataset, nil
-}
-
-func parseDate(v string, layouts ...string) (time.Time, error) {
-	var err error
-	for _, layout := range layouts {
-		date, ee := time.Parse(layout, v)
-		if ee == nil {
-			return date, nil
-		}
-		if err == nil {
-			err = ee
-		}
+	last := xs[len(xs)-1]
+	p.X.Max = math.Max(p.X.Max, last+forecastDays)
+	fct := hplot.NewFunction(fit.Predict)
+	fct.LineStyle.Color = clr
+	fct.LineStyle.Width = 2
+	fct.LineStyle.Dashes = plotutil.Dashes(2)
+	p.Add(fct)
+
+	doubling := fit.DoublingTime()
+	p.Legend.Add(fmt.Sprintf("%s - %s fit (doubling: %.1fd)", name, cfg.model, doubling), fct)
+
+	if hasInflection {
+		vline := hplot.VLine(inflection, nil, nil)
+		vline.Line.Color = clr
+		vline.Line.Dashes = plotutil.Dashes(3)
+		vline.Line.Width = 1
+		p.Add(vline)
 	}
-	return time.Time{}, err
-}
 
-func cleanup(title string, ds *Dataset) {
-	switch strings.ToLower(title) {
-	case "deaths":
-		tbl := ds.table["France"]
-		tbl[2] = 30   // 2020-03-09
-		tbl[10] = 175 // 2020-03-17
-		tbl[11] = 244 // 2020-03-18
-		tbl[12] = 372 // 2020-03-19
-		// tbl[26] = 4503 // 2020-04-02. number was actually correct (includes death toll from EHPADs)
-	case "confirmed":
-		tbl := ds.table["France"]
-		tbl[35] = 68605  // 2020-04-04
-		tbl[36] = 70478  // 2020-04-05
-		tbl[37] = 74390  // 2020-04-06
-		tbl[38] = 78167  // 2020-04-07
-		tbl[39] = 82048  // 2020-04-08
-		tbl[40] = 86344  // 2020-04-09
-		tbl[41] = 90676  // 2020-04-10
-		tbl[42] = 93790  // 2020-04-11
-		tbl[43] = 95403  // 2020-04-12
-		tbl[44] = 98076  // 2020-04-13
-		tbl[45] = 103573 // 2020-04-14
-		tbl[46] = 106206 // 2020-04-15
-		tbl[47] = 108847 // 2020-04-16
-		tbl[48] = 109252 // 2020-04-17
-		tbl[49] = 111821 // 2020-04-18
-		tbl[50] = 112606 // 2020-04-19
-		tbl[51] = 114657 // 2020-04-20
-		tbl[52] = 117324 // 2020-04-21
-	default:
-		panic(fmt.Errorf("invalid title: %q", title))
+	entry.Date = time.Now()
+	entry.Metric = cfg.metric
+	entry.Country = name
+	if err := model.AppendSidecar(*sidecar, entry); err != nil {
+		log.Printf("warning: could not persist model sidecar: %+v", err)
 	}
+
+	return nil
 }
 
 var (
@@ -370,12 +679,63 @@ const page = `<!DOCTYPE html>
 <html>
 	<head>
 		<title>COVID-19</title>
+		<style>
+			#tooltip {
+				position: absolute;
+				display: none;
+				background: rgba(0,0,0,0.75);
+				color: white;
+				padding: 4px 8px;
+				font: 12px monospace;
+				pointer-events: none;
+			}
+		</style>
 	</head>
 	<body>
 		<div id="content">
-			<img id="plot" src="/img-confirmed"/>
-			<img id="plot" src="/img-deaths"/>
+			<object id="plot-confirmed" class="plot" type="image/svg+xml" data="/svg-confirmed"></object>
+			<object id="plot-deaths" class="plot" type="image/svg+xml" data="/svg-deaths"></object>
 		</div>
+		<div id="tooltip"></div>
+		<script>
+			// Hover over a plot to show the nearest date/value from
+			// /data.json, since vg/vgsvg does not embed per-point metadata
+			// in the SVG itself.
+			async function setupTooltip(metric, objID, cutoff) {
+				let url = "/data.json?metric=" + metric;
+				if (cutoff !== undefined) {
+					url += "&cutoff=" + cutoff;
+				}
+				const resp = await fetch(url);
+				const data = await resp.json();
+				const obj = document.getElementById(objID);
+				const tooltip = document.getElementById("tooltip");
+
+				obj.addEventListener("mousemove", (ev) => {
+					const rect = obj.getBoundingClientRect();
+					const frac = (ev.clientX - rect.left) / rect.width;
+
+					let lines = [metric + ":"];
+					for (const name in data.countries) {
+						const s = data.countries[name];
+						const idx = Math.max(0, Math.min(s.dates.length - 1, Math.round(frac * (s.dates.length - 1))));
+						lines.push(name + " (" + s.dates[idx] + "): " + s.cumulative[idx]);
+					}
+
+					tooltip.textContent = lines.join("\n");
+					tooltip.style.left = (ev.pageX + 12) + "px";
+					tooltip.style.top = (ev.pageY + 12) + "px";
+					tooltip.style.display = "block";
+				});
+				obj.addEventListener("mouseleave", () => {
+					tooltip.style.display = "none";
+				});
+			}
+
+			setupTooltip("confirmed", "plot-confirmed");
+			// /svg-deaths is aligned on 10 deaths, not the default cutoff.
+			setupTooltip("deaths", "plot-deaths", 10);
+		</script>
 	</body>
 </html>
 `
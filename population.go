@@ -0,0 +1,49 @@
+// Copyright 2020 The covid19 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//go:embed population.csv
+var populationCSV string
+
+// Population maps a country name to its population, used to normalize
+// case/death counts per 100k inhabitants.
+var Population = mustLoadPopulation(populationCSV)
+
+func mustLoadPopulation(raw string) map[string]float64 {
+	pop, err := loadPopulation(raw)
+	if err != nil {
+		panic(err)
+	}
+	return pop
+}
+
+func loadPopulation(raw string) (map[string]float64, error) {
+	rows, err := csv.NewReader(strings.NewReader(raw)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not parse population.csv: %w", err)
+	}
+
+	pop := make(map[string]float64, len(rows))
+	for _, rec := range rows {
+		if len(rec) != 2 {
+			continue
+		}
+		name := strings.TrimSpace(rec[0])
+		v, err := strconv.ParseFloat(strings.TrimSpace(rec[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse population for %q: %w", name, err)
+		}
+		pop[name] = v
+	}
+	return pop, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 The covid19 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "math"
+
+// dailyDiffs turns a cumulative series into a daily-new-cases series,
+// clamping negative corrections (data revisions) to 0.
+func dailyDiffs(ys []float64) []float64 {
+	diffs := make([]float64, len(ys))
+	for i := range diffs {
+		if i == 0 {
+			continue
+		}
+		diffs[i] = math.Max(0, ys[i]-ys[i-1])
+	}
+	return diffs
+}
+
+// movingAverage returns the trailing moving average of ys over the given
+// window (in days), shrinking the window for the first window-1 points
+// rather than leaving them undefined.
+func movingAverage(ys []float64, window int) []float64 {
+	out := make([]float64, len(ys))
+	var sum float64
+	for i, y := range ys {
+		sum += y
+		lo := i - window + 1
+		if lo > 0 {
+			sum -= ys[lo-1]
+		} else {
+			lo = 0
+		}
+		out[i] = sum / float64(i-lo+1)
+	}
+	return out
+}
+
+// perCapita normalizes ys (a cumulative or daily count) to a rate per
+// 100,000 inhabitants.
+func perCapita(ys []float64, population float64) []float64 {
+	out := make([]float64, len(ys))
+	for i, y := range ys {
+		out[i] = y / population * 1e5
+	}
+	return out
+}
+
+// doublingTimes computes the instantaneous doubling time ln(2)/slope
+// of a cumulative series ys, where slope is the least-squares slope of
+// log(ys) over a trailing window (in days).
+func doublingTimes(ys []float64, window int) []float64 {
+	out := make([]float64, len(ys))
+	for i := range ys {
+		lo := i - window + 1
+		if lo < 0 {
+			lo = 0
+		}
+		out[i] = doublingTime(logSlope(ys[lo : i+1]))
+	}
+	return out
+}
+
+// logSlope returns the least-squares slope of log(ys) against the point
+// index 0..len(ys)-1. Non-positive values are skipped; NaN is returned if
+// fewer than two usable points remain.
+func logSlope(ys []float64) float64 {
+	var n, sx, sy, sxy, sxx float64
+	for i, y := range ys {
+		if y <= 0 {
+			continue
+		}
+		x := float64(i)
+		ly := math.Log(y)
+		n++
+		sx += x
+		sy += ly
+		sxy += x * ly
+		sxx += x * x
+	}
+	if n < 2 {
+		return math.NaN()
+	}
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return math.NaN()
+	}
+	return (n*sxy - sx*sy) / den
+}
+
+// maxDoublingTime caps the reported doubling time, in days, for a
+// plateaued or declining series. The series is not currently doubling at
+// all in that case, but an infinite or NaN value can't be plotted, so a
+// large-but-finite value is reported instead.
+const maxDoublingTime = 1000
+
+// doublingTime converts a growth rate (slope of log(y)) to a doubling
+// time in days, capped at maxDoublingTime. For a non-positive or
+// undefined rate, the quantity is not currently doubling, so the cap is
+// reported rather than +Inf, which plotter.CopyXYs rejects outright.
+func doublingTime(rate float64) float64 {
+	if math.IsNaN(rate) || rate <= 0 {
+		return maxDoublingTime
+	}
+	d := math.Ln2 / rate
+	if d > maxDoublingTime {
+		return maxDoublingTime
+	}
+	return d
+}
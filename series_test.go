@@ -0,0 +1,100 @@
+// Copyright 2020 The covid19 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDailyDiffs(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		ys   []float64
+		want []float64
+	}{
+		{name: "empty", ys: nil, want: []float64{}},
+		{name: "monotonic", ys: []float64{1, 3, 6, 10}, want: []float64{0, 2, 3, 4}},
+		{name: "revision clamped to 0", ys: []float64{10, 8, 12}, want: []float64{0, 0, 4}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := dailyDiffs(tc.ys)
+			if !floatsEqual(got, tc.want) {
+				t.Errorf("dailyDiffs(%v) = %v, want %v", tc.ys, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMovingAverage(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		ys     []float64
+		window int
+		want   []float64
+	}{
+		{name: "shorter than window", ys: []float64{2, 4}, window: 7, want: []float64{2, 3}},
+		{
+			name:   "full window",
+			ys:     []float64{1, 2, 3, 4, 5},
+			window: 3,
+			want:   []float64{1, 1.5, 2, 3, 4},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := movingAverage(tc.ys, tc.window)
+			if !floatsEqual(got, tc.want) {
+				t.Errorf("movingAverage(%v, %d) = %v, want %v", tc.ys, tc.window, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPerCapita(t *testing.T) {
+	got := perCapita([]float64{10, 20}, 1e6)
+	want := []float64{1, 2}
+	if !floatsEqual(got, want) {
+		t.Errorf("perCapita = %v, want %v", got, want)
+	}
+}
+
+func TestDoublingTimeDoublingSeries(t *testing.T) {
+	// A series that exactly doubles every day has slope ln(2) and should
+	// report a one-day doubling time.
+	ys := make([]float64, 10)
+	for i := range ys {
+		ys[i] = math.Pow(2, float64(i))
+	}
+	got := doublingTimes(ys, ma7Window)
+	last := got[len(got)-1]
+	if math.Abs(last-1) > 1e-6 {
+		t.Errorf("doublingTimes for a daily-doubling series = %v, want ~1", last)
+	}
+}
+
+func TestDoublingTimeCapsFlatSeries(t *testing.T) {
+	ys := []float64{100, 100, 100, 100, 100}
+	got := doublingTimes(ys, ma7Window)
+	for i, v := range got {
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			t.Fatalf("doublingTimes[%d] = %v, want a finite, plottable value", i, v)
+		}
+		if v != maxDoublingTime {
+			t.Errorf("doublingTimes[%d] = %v, want maxDoublingTime (%v) for a flat series", i, v, maxDoublingTime)
+		}
+	}
+}
+
+func floatsEqual(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
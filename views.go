@@ -0,0 +1,108 @@
+// Copyright 2020 The covid19 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"go-hep.org/x/hep/hplot"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotutil"
+)
+
+// ma7Window is the trailing window, in days, used for the 7-day moving
+// average and doubling-time views.
+const ma7Window = 7
+
+// doublingThreshold is the doubling time, in days, below which growth is
+// highlighted as fast in the "doubling" view.
+const doublingThreshold = 3
+
+// addViewTile lays out one of the derived-series tiles ("ma7",
+// "percapita", "doubling") on p, sharing the same per-country,
+// cutoff-aligned X axis as the cumulative/daily tiles.
+func addViewTile(p *hplot.Plot, view string, cfg plotConfig, countries []string, dataset map[string][]float64, date time.Time) error {
+	switch view {
+	case "ma7":
+		p.Title.Text = "CoVid-19 - " + cfg.metric + " (7-day avg) - " + date.Format("2006-01-02")
+		p.X.Label.Text = fmt.Sprintf("Days from first %d confirmed cases", int(cfg.cutoff))
+		p.X.Tick.Marker = hplot.Ticks{N: 20}
+		p.Legend.Left = true
+		p.Legend.Top = true
+		for i, name := range countries {
+			ys := movingAverage(dailyDiffs(dataset[name]), ma7Window)
+			if err := addSeries(p, i, name, ys, fmt.Sprintf("%8.0f %s", ys[len(ys)-1], name)); err != nil {
+				return err
+			}
+		}
+
+	case "percapita":
+		p.Title.Text = "CoVid-19 - " + cfg.metric + " per 100k - " + date.Format("2006-01-02")
+		p.X.Label.Text = fmt.Sprintf("Days from first %d confirmed cases", int(cfg.cutoff))
+		p.X.Tick.Marker = hplot.Ticks{N: 20}
+		p.Legend.Left = true
+		p.Legend.Top = true
+		for i, name := range countries {
+			population, ok := Population[name]
+			if !ok {
+				log.Printf("warning: no known population for %q, omitting from the per-100k view", name)
+				continue
+			}
+			ys := perCapita(dataset[name], population)
+			if err := addSeries(p, i, name, ys, fmt.Sprintf("%8.1f %s", ys[len(ys)-1], name)); err != nil {
+				return err
+			}
+		}
+
+	case "doubling":
+		p.Title.Text = "CoVid-19 - " + cfg.metric + " doubling time - " + date.Format("2006-01-02")
+		p.X.Label.Text = fmt.Sprintf("Days from first %d confirmed cases", int(cfg.cutoff))
+		p.X.Tick.Marker = hplot.Ticks{N: 20}
+		p.Y.Label.Text = "doubling time (days)"
+		p.Y.Scale = plot.LogScale{}
+		p.Y.Tick.Marker = plot.LogTicks{}
+		p.Legend.Left = true
+		p.Legend.Top = true
+		for i, name := range countries {
+			ys := doublingTimes(dataset[name], ma7Window)
+			if err := addSeries(p, i, name, ys, name); err != nil {
+				return err
+			}
+		}
+		band := hplot.HLine(doublingThreshold, nil, nil)
+		band.Line.Width = 2
+		band.Line.Dashes = plotutil.Dashes(1)
+		p.Add(band)
+		p.Legend.Add(fmt.Sprintf("T_d < %d days", doublingThreshold), band)
+
+	default:
+		return fmt.Errorf("unknown view %q", view)
+	}
+
+	p.Add(hplot.NewGrid())
+	return nil
+}
+
+// addSeries draws ys (one point per index, aligned with the country's own
+// cutoff-trimmed X axis) on p as the i-th colored line, labeling it with
+// legendText.
+func addSeries(p *hplot.Plot, i int, name string, ys []float64, legendText string) error {
+	xs := make([]float64, len(ys))
+	for i := range xs {
+		xs[i] = float64(i)
+	}
+	xys := hplot.ZipXY(xs, ys)
+	line, err := hplot.NewLine(xys)
+	if err != nil {
+		return fmt.Errorf("could not create line plot for %q: %w", name, err)
+	}
+	line.Color = softcolor(i)
+	line.Width = 2
+	p.Add(line)
+	p.Legend.Add(legendText, line)
+	return nil
+}